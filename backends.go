@@ -0,0 +1,220 @@
+package updater
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// NexusLister lists builds via the Nexus Repository Manager v3 REST search API
+// (/service/rest/v1/search), which returns JSON assets together with their checksums.
+type NexusLister struct {
+	BaseURL        string
+	Repository     string
+	Fields         []field
+	FieldSeparator string
+}
+
+// NewNexusLister returns a NexusLister for repositoryURL. It recognizes the standard Nexus 3
+// layout "https://host/repository/<repository>", where the REST search API lives at the server
+// root ("https://host/service/rest/v1/search") rather than under the repository's own path. As a
+// fallback for URLs that don't follow that convention, the last path segment is taken as the
+// repository name and everything before it as the server root.
+func NewNexusLister(repositoryURL string, fields []field, separator string) *NexusLister {
+	trimmed := strings.TrimSuffix(repositoryURL, "/")
+
+	const repositorySegment = "/repository/"
+	base, repository := trimmed, path.Base(trimmed)
+	if idx := strings.Index(trimmed, repositorySegment); idx >= 0 {
+		base = trimmed[:idx]
+		repository = strings.TrimPrefix(trimmed[idx:], repositorySegment)
+	} else {
+		base = strings.TrimSuffix(strings.TrimSuffix(trimmed, repository), "/")
+	}
+
+	return &NexusLister{
+		BaseURL:        base,
+		Repository:     repository,
+		Fields:         fields,
+		FieldSeparator: separator,
+	}
+}
+
+type nexusSearchResponse struct {
+	Items []struct {
+		Path   string `json:"path"`
+		Assets []struct {
+			DownloadURL string `json:"downloadUrl"`
+			Path        string `json:"path"`
+			Checksum    struct {
+				SHA256 string `json:"sha256"`
+				MD5    string `json:"md5"`
+			} `json:"checksum"`
+		} `json:"assets"`
+	} `json:"items"`
+	ContinuationToken string `json:"continuationToken"`
+}
+
+// List implements RepositoryLister.
+func (l *NexusLister) List() ([]*BuildInfo, error) {
+	list := make([]*BuildInfo, 0)
+	continuationToken := ""
+	for {
+		url := fmt.Sprintf("%s/service/rest/v1/search?repository=%s", l.BaseURL, l.Repository)
+		if continuationToken != "" {
+			url += "&continuationToken=" + continuationToken
+		}
+
+		resp, err := http.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		var page nexusSearchResponse
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("cannot parse Nexus search response: %v", err)
+		}
+
+		for _, item := range page.Items {
+			for _, asset := range item.Assets {
+				build := tokenizeBuildName(l.Fields, l.FieldSeparator, path.Base(asset.Path))
+				if build == nil {
+					continue
+				}
+				build.URL = asset.DownloadURL
+				build.SHA256 = asset.Checksum.SHA256
+				build.MD5 = asset.Checksum.MD5
+				list = append(list, build)
+			}
+		}
+
+		if page.ContinuationToken == "" {
+			break
+		}
+		continuationToken = page.ContinuationToken
+	}
+	return list, nil
+}
+
+// GitHubLister lists builds from a GitHub repository's latest release assets.
+type GitHubLister struct {
+	OwnerRepo      string
+	Fields         []field
+	FieldSeparator string
+}
+
+// NewGitHubLister returns a GitHubLister for ownerRepo, of the form "owner/repo".
+func NewGitHubLister(ownerRepo string, fields []field, separator string) *GitHubLister {
+	return &GitHubLister{OwnerRepo: ownerRepo, Fields: fields, FieldSeparator: separator}
+}
+
+type gitHubRelease struct {
+	Assets []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// List implements RepositoryLister.
+func (l *GitHubLister) List() ([]*BuildInfo, error) {
+	url := fmt.Sprintf("https://api.github.com/repos/%s/releases/latest", l.OwnerRepo)
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var release gitHubRelease
+	if err := json.Unmarshal(body, &release); err != nil {
+		return nil, fmt.Errorf("cannot parse GitHub release response: %v", err)
+	}
+
+	list := make([]*BuildInfo, 0, len(release.Assets))
+	for _, asset := range release.Assets {
+		build := tokenizeBuildName(l.Fields, l.FieldSeparator, asset.Name)
+		if build == nil {
+			continue
+		}
+		build.URL = asset.BrowserDownloadURL
+		list = append(list, build)
+	}
+	return list, nil
+}
+
+// S3Lister lists builds from an S3 bucket's XML ListBucketResult listing, always fetched from
+// https://<bucket>.s3.amazonaws.com/; other hosts serving the same XML format (such as GCS) are
+// not addressed.
+type S3Lister struct {
+	Bucket         string
+	Prefix         string
+	Fields         []field
+	FieldSeparator string
+}
+
+// NewS3Lister returns an S3Lister for bucketPrefix, of the form "bucket[/prefix]".
+func NewS3Lister(bucketPrefix string, fields []field, separator string) *S3Lister {
+	bucket := bucketPrefix
+	prefix := ""
+	if idx := strings.Index(bucketPrefix, "/"); idx >= 0 {
+		bucket, prefix = bucketPrefix[:idx], bucketPrefix[idx+1:]
+	}
+	return &S3Lister{Bucket: bucket, Prefix: prefix, Fields: fields, FieldSeparator: separator}
+}
+
+type s3ListBucketResult struct {
+	Contents []struct {
+		Key  string `xml:"Key"`
+		ETag string `xml:"ETag"`
+	} `xml:"Contents"`
+}
+
+// List implements RepositoryLister.
+func (l *S3Lister) List() ([]*BuildInfo, error) {
+	url := fmt.Sprintf("https://%s.s3.amazonaws.com/", l.Bucket)
+	if l.Prefix != "" {
+		url += "?prefix=" + l.Prefix
+	}
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result s3ListBucketResult
+	if err := xml.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("cannot parse S3 bucket listing: %v", err)
+	}
+
+	list := make([]*BuildInfo, 0, len(result.Contents))
+	for _, entry := range result.Contents {
+		build := tokenizeBuildName(l.Fields, l.FieldSeparator, path.Base(entry.Key))
+		if build == nil {
+			continue
+		}
+		build.URL = fmt.Sprintf("https://%s.s3.amazonaws.com/%s", l.Bucket, entry.Key)
+		// A plain ETag (not a multipart upload's "-N" suffixed one) is the object's MD5.
+		if etag := strings.Trim(entry.ETag, `"`); !strings.Contains(etag, "-") {
+			build.MD5 = etag
+		}
+		list = append(list, build)
+	}
+	return list, nil
+}
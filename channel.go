@@ -0,0 +1,34 @@
+package updater
+
+import "strings"
+
+// Channel decides whether a build's pre-release identifier belongs to the release channel a
+// caller wants updates from. It composes with Matcher the same way RolloutMatcher does: a build
+// must satisfy both.
+type Channel func(build *BuildInfo) bool
+
+// ChannelStable only accepts builds with no pre-release identifier at all.
+func ChannelStable(build *BuildInfo) bool {
+	return build.Version == nil || len(build.Version.Pre) == 0
+}
+
+// ChannelBeta accepts stable builds plus "beta.*" and "rc.*" pre-releases.
+func ChannelBeta(build *BuildInfo) bool {
+	if ChannelStable(build) {
+		return true
+	}
+	pre := prereleaseIdentifier(build)
+	return strings.HasPrefix(pre, "beta") || strings.HasPrefix(pre, "rc")
+}
+
+// ChannelAlpha accepts any pre-release identifier, including "alpha.*".
+func ChannelAlpha(build *BuildInfo) bool {
+	return true
+}
+
+func prereleaseIdentifier(build *BuildInfo) string {
+	if build.Version == nil || len(build.Version.Pre) == 0 {
+		return ""
+	}
+	return build.Version.Pre[0].VersionStr
+}
@@ -0,0 +1,165 @@
+package updater
+
+import (
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/blang/semver/v4"
+)
+
+// RepositoryLister lists the candidate builds published by a repository. Implementations are free
+// to populate whatever checksum metadata their backend exposes (BuildInfo.MD5, BuildInfo.SHA256,
+// BuildInfo.MD5URL, BuildInfo.SHA256URL, BuildInfo.SignatureURL); Updater only requires URL and
+// File to be set.
+type RepositoryLister interface {
+	List() ([]*BuildInfo, error)
+}
+
+// newLister builds the default RepositoryLister for config.Repository, selecting a backend from
+// its URL scheme.
+func newLister(config Config) RepositoryLister {
+	switch {
+	case strings.HasPrefix(config.Repository, "nexus+"):
+		return NewNexusLister(strings.TrimPrefix(config.Repository, "nexus+"), config.Fields, config.FieldSeparator)
+	case strings.HasPrefix(config.Repository, "github://"):
+		return NewGitHubLister(strings.TrimPrefix(config.Repository, "github://"), config.Fields, config.FieldSeparator)
+	case strings.HasPrefix(config.Repository, "s3://"):
+		return NewS3Lister(strings.TrimPrefix(config.Repository, "s3://"), config.Fields, config.FieldSeparator)
+	default:
+		return NewHTMLLister(config.Repository, config.Fields, config.FieldSeparator)
+	}
+}
+
+// sidecarSuffixes maps the file extension of a checksum/signature sidecar entry to the BuildInfo
+// field it should be attached to. Each algorithm gets its own field so a repository publishing
+// both a .md5 and a .sha256 sidecar for the same build keeps both URLs addressable.
+var sidecarSuffixes = map[string]func(build *BuildInfo, url string){
+	".sha256": func(build *BuildInfo, url string) { build.SHA256URL = url },
+	".md5":    func(build *BuildInfo, url string) { build.MD5URL = url },
+	".sig":    func(build *BuildInfo, url string) { build.SignatureURL = url },
+	".asc":    func(build *BuildInfo, url string) { build.SignatureURL = url },
+}
+
+// sidecarFor reports whether name is a checksum/signature sidecar file, returning the suffix that
+// identifies its kind and the base build file name it is attached to.
+func sidecarFor(name string) (suffix string, base string, ok bool) {
+	for ext := range sidecarSuffixes {
+		if strings.HasSuffix(name, ext) {
+			return ext, strings.TrimSuffix(name, ext), true
+		}
+	}
+	return "", "", false
+}
+
+// HTMLLister lists builds by scraping `<a href>` anchors out of an HTML directory listing, the
+// layout served by plain static repositories (and by Nexus's raw/hosted HTML views).
+type HTMLLister struct {
+	Repository     string
+	Fields         []field
+	FieldSeparator string
+}
+
+// NewHTMLLister returns an HTMLLister reading repository, tokenizing anchor text according to
+// fields and separator.
+func NewHTMLLister(repository string, fields []field, separator string) *HTMLLister {
+	return &HTMLLister{Repository: repository, Fields: fields, FieldSeparator: separator}
+}
+
+// List implements RepositoryLister.
+func (l *HTMLLister) List() ([]*BuildInfo, error) {
+	resp, err := http.Get(l.Repository)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	re := regexp.MustCompile("<a [^>]*href=\"([^\"]+)\"[^>]*>([^<]+)</a>")
+	matches := re.FindAllSubmatch(body, -1)
+
+	type sidecar struct {
+		suffix string
+		url    string
+	}
+
+	list := make([]*BuildInfo, 0)
+	byFile := map[string]*BuildInfo{}
+	sidecars := make(map[string][]sidecar) // base file name -> every sidecar found for it
+	for _, match := range matches {
+		name := string(match[2])
+		url := string(match[1])
+		if suffix, base, ok := sidecarFor(name); ok {
+			sidecars[base] = append(sidecars[base], sidecar{suffix, url})
+			continue
+		}
+		build := tokenizeBuildName(l.Fields, l.FieldSeparator, name)
+		if build == nil {
+			continue
+		}
+		build.URL = url
+		list = append(list, build)
+		byFile[build.File] = build
+	}
+	for base, entries := range sidecars {
+		build, ok := byFile[base]
+		if !ok {
+			continue
+		}
+		for _, entry := range entries {
+			sidecarSuffixes[entry.suffix](build, entry.url)
+		}
+	}
+	return list, nil
+}
+
+// tokenizeBuildName splits a build file name into a BuildInfo according to fields and separator,
+// the convention shared by every lister that only has a bare file name to work with. A version
+// carrying a pre-release identifier (e.g. "1.2.0-rc1") is itself split by separator, so any extra
+// tokens beyond len(fields) are folded back into the version field.
+func tokenizeBuildName(fields []field, separator string, buildName string) *BuildInfo {
+	split := strings.Split(strings.TrimSuffix(buildName, ".exe"), separator)
+	versionIdx := indexOfField(fields, FieldVersion)
+	extra := len(split) - len(fields)
+	if versionIdx < 0 || extra < 0 {
+		return nil
+	}
+
+	tokens := map[field]string{}
+	pos := 0
+	for key, f := range fields {
+		if key == versionIdx {
+			tokens[f] = strings.Join(split[pos:pos+1+extra], separator)
+			pos += 1 + extra
+			continue
+		}
+		tokens[f] = split[pos]
+		pos++
+	}
+
+	version, err := semver.Parse(tokens[FieldVersion])
+	if err != nil {
+		return nil
+	}
+
+	return &BuildInfo{
+		Name:    tokens[FieldName],
+		Version: &version,
+		Os:      tokens[FieldOs],
+		Arch:    tokens[FieldArch],
+		File:    buildName,
+	}
+}
+
+func indexOfField(fields []field, target field) int {
+	for i, f := range fields {
+		if f == target {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,105 @@
+package updater
+
+import "testing"
+
+func TestTokenizeBuildName(t *testing.T) {
+	fields := []field{FieldName, FieldVersion, FieldOs, FieldArch}
+
+	cases := []struct {
+		name        string
+		buildName   string
+		wantName    string
+		wantVersion string
+		wantOs      string
+		wantArch    string
+		wantNil     bool
+	}{
+		{
+			name:        "plain version",
+			buildName:   "myapp-1.2.0-linux-amd64",
+			wantName:    "myapp",
+			wantVersion: "1.2.0",
+			wantOs:      "linux",
+			wantArch:    "amd64",
+		},
+		{
+			name:        "windows exe suffix is trimmed",
+			buildName:   "myapp-1.2.0-windows-amd64.exe",
+			wantName:    "myapp",
+			wantVersion: "1.2.0",
+			wantOs:      "windows",
+			wantArch:    "amd64",
+		},
+		{
+			name:        "single-token pre-release",
+			buildName:   "myapp-1.2.0-rc1-linux-amd64",
+			wantName:    "myapp",
+			wantVersion: "1.2.0-rc1",
+			wantOs:      "linux",
+			wantArch:    "amd64",
+		},
+		{
+			name:        "build metadata doesn't need folding (no separator inside it)",
+			buildName:   "myapp-1.2.0+build42-linux-amd64",
+			wantName:    "myapp",
+			wantVersion: "1.2.0+build42",
+			wantOs:      "linux",
+			wantArch:    "amd64",
+		},
+		{
+			name:        "pre-release itself containing the field separator",
+			buildName:   "myapp-1.2.0-rc-1-linux-amd64",
+			wantName:    "myapp",
+			wantVersion: "1.2.0-rc-1",
+			wantOs:      "linux",
+			wantArch:    "amd64",
+		},
+		{
+			name:      "too few tokens",
+			buildName: "myapp-1.2.0-linux",
+			wantNil:   true,
+		},
+		{
+			name:      "version doesn't parse as semver",
+			buildName: "myapp-notaversion-linux-amd64",
+			wantNil:   true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			build := tokenizeBuildName(fields, "-", c.buildName)
+			if c.wantNil {
+				if build != nil {
+					t.Fatalf("tokenizeBuildName(%q) = %+v, want nil", c.buildName, build)
+				}
+				return
+			}
+			if build == nil {
+				t.Fatalf("tokenizeBuildName(%q) = nil, want a build", c.buildName)
+			}
+			if build.Name != c.wantName {
+				t.Errorf("Name = %q, want %q", build.Name, c.wantName)
+			}
+			if build.Version == nil || build.Version.String() != c.wantVersion {
+				t.Errorf("Version = %v, want %q", build.Version, c.wantVersion)
+			}
+			if build.Os != c.wantOs {
+				t.Errorf("Os = %q, want %q", build.Os, c.wantOs)
+			}
+			if build.Arch != c.wantArch {
+				t.Errorf("Arch = %q, want %q", build.Arch, c.wantArch)
+			}
+			if build.File != c.buildName {
+				t.Errorf("File = %q, want %q", build.File, c.buildName)
+			}
+		})
+	}
+}
+
+func TestTokenizeBuildNameRequiresVersionField(t *testing.T) {
+	fields := []field{FieldName, FieldOs, FieldArch}
+	if build := tokenizeBuildName(fields, "-", "myapp-linux-amd64"); build != nil {
+		t.Fatalf("tokenizeBuildName() = %+v, want nil when fields has no FieldVersion", build)
+	}
+}
@@ -0,0 +1,134 @@
+package updater
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// rolloutCohort is one entry of a repository's rollout.json companion file, advertising how far a
+// version has been ramped out.
+type rolloutCohort struct {
+	Version string  `json:"version"`
+	Cursor  float64 `json:"cursor"`
+}
+
+// RolloutMatcher restricts eligible builds to those whose rollout cursor, as advertised by the
+// repository's rollout.json, already covers this node. A version absent from rollout.json is
+// considered fully available, so repositories that don't stage a given release aren't blocked.
+type RolloutMatcher struct {
+	cohorts  map[string]float64
+	fraction float64
+}
+
+// NewRolloutMatcher fetches rollout.json next to repository and derives this node's stable
+// position in [0,1) from a persisted identifier under statePath (or, if statePath is empty, from
+// the hostname and binary path).
+func NewRolloutMatcher(repository string, statePath string) (*RolloutMatcher, error) {
+	fraction, err := nodeFraction(statePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot derive node rollout position: %v", err)
+	}
+
+	rolloutURL := repository
+	if strings.HasSuffix(rolloutURL, "/") {
+		rolloutURL += "rollout.json"
+	} else {
+		rolloutURL += "/rollout.json"
+	}
+
+	resp, err := http.Get(rolloutURL)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch %s: %v", rolloutURL, err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %v", rolloutURL, err)
+	}
+
+	var entries []rolloutCohort
+	if err := json.Unmarshal(body, &entries); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %v", rolloutURL, err)
+	}
+
+	cohorts := make(map[string]float64, len(entries))
+	for _, entry := range entries {
+		cohorts[entry.Version] = entry.Cursor
+	}
+
+	return &RolloutMatcher{cohorts: cohorts, fraction: fraction}, nil
+}
+
+// Eligible reports whether build has reached this node's cohort.
+func (m *RolloutMatcher) Eligible(build *BuildInfo) bool {
+	if build == nil || build.Version == nil {
+		return false
+	}
+	cursor, staged := m.cohorts[build.Version.String()]
+	if !staged {
+		return true
+	}
+	return m.fraction < cursor
+}
+
+// nodeFraction maps this node's stable identifier to a float in [0,1).
+func nodeFraction(statePath string) (float64, error) {
+	id, err := nodeID(statePath)
+	if err != nil {
+		return 0, err
+	}
+	sum := sha256.Sum256([]byte(id))
+	return float64(binary.BigEndian.Uint64(sum[:8])>>11) / (1 << 53), nil
+}
+
+// nodeID returns a stable per-node identifier. If statePath is set, a UUID is generated once and
+// persisted there; otherwise the identifier is derived from the hostname and binary path.
+func nodeID(statePath string) (string, error) {
+	if statePath == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			host = "unknown-host"
+		}
+		exe, err := os.Executable()
+		if err != nil {
+			exe = "unknown-binary"
+		}
+		return host + exe, nil
+	}
+
+	idPath := filepath.Join(statePath, "node-id")
+	if data, err := ioutil.ReadFile(idPath); err == nil && len(strings.TrimSpace(string(data))) > 0 {
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	id, err := newUUID()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(statePath, 0755); err != nil {
+		return "", fmt.Errorf("cannot create state path %s: %v", statePath, err)
+	}
+	if err := ioutil.WriteFile(idPath, []byte(id), 0644); err != nil {
+		return "", fmt.Errorf("cannot persist node id to %s: %v", idPath, err)
+	}
+	return id, nil
+}
+
+// newUUID generates a random (version 4) UUID without pulling in an external dependency.
+func newUUID() (string, error) {
+	var buf [16]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return "", err
+	}
+	buf[6] = (buf[6] & 0x0f) | 0x40
+	buf[8] = (buf[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", buf[0:4], buf[4:6], buf[6:8], buf[8:10], buf[10:16]), nil
+}
@@ -0,0 +1,89 @@
+package updater
+
+import (
+	"testing"
+
+	"github.com/blang/semver/v4"
+)
+
+func newParsedVersion(raw string) (*semver.Version, error) {
+	v, err := semver.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func TestNodeFractionRange(t *testing.T) {
+	statePath := t.TempDir()
+	fraction, err := nodeFraction(statePath)
+	if err != nil {
+		t.Fatalf("nodeFraction() error = %v", err)
+	}
+	if fraction < 0 || fraction >= 1 {
+		t.Fatalf("nodeFraction() = %v, want a value in [0,1)", fraction)
+	}
+}
+
+func TestNodeFractionStableAcrossCalls(t *testing.T) {
+	statePath := t.TempDir()
+
+	first, err := nodeFraction(statePath)
+	if err != nil {
+		t.Fatalf("nodeFraction() error = %v", err)
+	}
+	second, err := nodeFraction(statePath)
+	if err != nil {
+		t.Fatalf("nodeFraction() error = %v", err)
+	}
+	if first != second {
+		t.Fatalf("nodeFraction() changed across calls with the same statePath: %v != %v", first, second)
+	}
+}
+
+func TestNodeFractionDiffersAcrossStatePaths(t *testing.T) {
+	first, err := nodeFraction(t.TempDir())
+	if err != nil {
+		t.Fatalf("nodeFraction() error = %v", err)
+	}
+	second, err := nodeFraction(t.TempDir())
+	if err != nil {
+		t.Fatalf("nodeFraction() error = %v", err)
+	}
+	if first == second {
+		t.Fatalf("nodeFraction() gave the same value for two distinct, freshly generated node ids: %v", first)
+	}
+}
+
+func TestRolloutMatcherEligible(t *testing.T) {
+	version100, err := newParsedVersion("1.0.0")
+	if err != nil {
+		t.Fatalf("cannot parse version: %v", err)
+	}
+	version200, err := newParsedVersion("2.0.0")
+	if err != nil {
+		t.Fatalf("cannot parse version: %v", err)
+	}
+
+	matcher := &RolloutMatcher{
+		cohorts:  map[string]float64{"1.0.0": 0.5},
+		fraction: 0.25,
+	}
+
+	if !matcher.Eligible(&BuildInfo{Version: version100}) {
+		t.Errorf("build at cursor 0.5 should be eligible for a node at fraction 0.25")
+	}
+
+	matcher.fraction = 0.75
+	if matcher.Eligible(&BuildInfo{Version: version100}) {
+		t.Errorf("build at cursor 0.5 should not be eligible for a node at fraction 0.75")
+	}
+
+	if !matcher.Eligible(&BuildInfo{Version: version200}) {
+		t.Errorf("a version absent from rollout.json should be treated as fully available")
+	}
+
+	if matcher.Eligible(&BuildInfo{Version: nil}) {
+		t.Errorf("a build with no parsed version should never be eligible")
+	}
+}
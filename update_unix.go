@@ -0,0 +1,42 @@
+//go:build !windows
+// +build !windows
+
+package updater
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// installUpdate swaps targetPath for newPath. POSIX rename is atomic and safe to use directly,
+// even while targetPath is the currently running executable, so the previous binary only needs to
+// be preserved as oldPath for Rollback; it isn't in the way of the swap itself.
+func installUpdate(newPath, targetPath, oldPath string) error {
+	if _, err := os.Stat(targetPath); err == nil {
+		os.Remove(oldPath)
+		if err := os.Link(targetPath, oldPath); err != nil {
+			return fmt.Errorf("cannot preserve previous version at %s: %v", oldPath, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("cannot stat %s: %v", targetPath, err)
+	}
+	if err := os.Rename(newPath, targetPath); err != nil {
+		return fmt.Errorf("cannot move %s to %s: %v", newPath, targetPath, err)
+	}
+	return nil
+}
+
+// rollbackUpdate restores oldPath over targetPath.
+func rollbackUpdate(oldPath, targetPath string) error {
+	return os.Rename(oldPath, targetPath)
+}
+
+// Restart re-executes the updated binary in place, replacing the current process image.
+func (u Updater) Restart() error {
+	path, err := u.targetPath()
+	if err != nil {
+		return err
+	}
+	return syscall.Exec(path, os.Args, os.Environ())
+}
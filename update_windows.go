@@ -0,0 +1,58 @@
+//go:build windows
+// +build windows
+
+package updater
+
+import (
+	"fmt"
+	"os"
+)
+
+// installUpdate swaps targetPath for newPath. The running executable keeps its file locked on
+// Windows, so its name must be freed by renaming it to oldPath before the new binary can take its
+// place (the classic side-by-side swap).
+func installUpdate(newPath, targetPath, oldPath string) error {
+	if _, err := os.Stat(targetPath); err == nil {
+		os.Remove(oldPath)
+		if err := os.Rename(targetPath, oldPath); err != nil {
+			return fmt.Errorf("cannot move running executable %s to %s: %v", targetPath, oldPath, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("cannot stat %s: %v", targetPath, err)
+	}
+	if err := os.Rename(newPath, targetPath); err != nil {
+		return fmt.Errorf("cannot move %s to %s: %v", newPath, targetPath, err)
+	}
+	return nil
+}
+
+// rollbackUpdate restores oldPath over targetPath, freeing targetPath's locked name first.
+func rollbackUpdate(oldPath, targetPath string) error {
+	displaced := targetPath + ".rollback"
+	os.Remove(displaced)
+	if err := os.Rename(targetPath, displaced); err != nil {
+		return fmt.Errorf("cannot move running executable %s to %s: %v", targetPath, displaced, err)
+	}
+	if err := os.Rename(oldPath, targetPath); err != nil {
+		return err
+	}
+	os.Remove(displaced)
+	return nil
+}
+
+// Restart starts the updated binary as a new process and exits the current one, since Windows
+// can't re-exec into a new image over a running process.
+func (u Updater) Restart() error {
+	path, err := u.targetPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.StartProcess(path, os.Args, &os.ProcAttr{
+		Files: []*os.File{os.Stdin, os.Stdout, os.Stderr},
+		Env:   os.Environ(),
+	}); err != nil {
+		return fmt.Errorf("cannot start updated binary %s: %v", path, err)
+	}
+	os.Exit(0)
+	return nil
+}
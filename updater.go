@@ -4,15 +4,12 @@ package updater
 import (
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
 	"os"
-	"regexp"
 	"runtime"
 	"sort"
-	"strings"
 
-	"github.com/coreos/go-semver/semver"
+	"github.com/blang/semver/v4"
 )
 
 // BuildInfo is an entry of the repository
@@ -23,7 +20,17 @@ type BuildInfo struct {
 	Os      string
 	Arch    string
 	MD5     string
+	SHA256  string
 	URL     string
+	// MD5URL is the location of a sidecar .md5 file holding the expected MD5 checksum, if one was
+	// found alongside this build in the repository.
+	MD5URL string
+	// SHA256URL is the location of a sidecar .sha256 file holding the expected SHA256 checksum, if
+	// one was found alongside this build in the repository.
+	SHA256URL string
+	// SignatureURL is the location of a sidecar detached signature file, if any was found alongside
+	// this build in the repository.
+	SignatureURL string
 }
 
 type by func(build1, build2 BuildInfo) bool
@@ -50,10 +57,34 @@ type Config struct {
 	SortCriteria sortCriteria
 	// Matcher is a pointer to the wanted Matcher func
 	Matcher *Matcher
-	// Repository is the url of the repository where updates are to be found
+	// Repository is the url of the repository where updates are to be found. Its scheme selects
+	// the default RepositoryLister when Lister is not set: a plain http(s):// URL scrapes an HTML
+	// directory listing, nexus+https:// queries the Nexus REST API, github://owner/repo reads
+	// GitHub Releases, and s3://bucket/prefix lists an S3 bucket's XML listing.
 	Repository string
+	// Lister, when set, overrides the RepositoryLister derived from Repository's scheme.
+	Lister RepositoryLister
 	// TmpPattern is a sprintf pattern defining the local temporary storage for downloaded files
 	TmpPattern string
+	// Verifier, when set, is used to authenticate a downloaded build before it is installed.
+	// UpdateTo aborts and removes the temporary file if verification fails.
+	Verifier Verifier
+	// RolloutMatcher, when set, restricts FindLatest to builds that have reached this node's
+	// cohort in a staged rollout. It is combined with Matcher: a build must satisfy both. Build one
+	// with NewRolloutMatcher(repository, statePath), which also controls where the per-node rollout
+	// identifier is persisted.
+	RolloutMatcher *RolloutMatcher
+	// ForceLatest bypasses RolloutMatcher entirely, for manual QA of a build still ramping up.
+	ForceLatest bool
+	// Channel filters builds by pre-release identifier, e.g. ChannelStable, ChannelBeta or
+	// ChannelAlpha. Defaults to nil, which accepts any pre-release.
+	Channel Channel
+	// CurrentVersion is the version of the binary currently installed. When set, FindLatest
+	// refuses to return a build whose major version is higher, unless AllowMajorJump is true.
+	CurrentVersion string
+	// AllowMajorJump opts in to a build that bumps the major version over CurrentVersion, for
+	// repositories that publish v2+ artifacts under the same binary name as a breaking change.
+	AllowMajorJump bool
 }
 
 // Updater is the main object
@@ -105,6 +136,34 @@ func New(config Config) Updater {
 	if config.TmpPattern == "" {
 		config.TmpPattern = string(os.PathSeparator) + "tmp" + string(os.PathSeparator) + "%s.tmp"
 	}
+	if config.Lister == nil {
+		config.Lister = newLister(config)
+	}
+	if config.Channel != nil {
+		base := *config.Matcher
+		channel := config.Channel
+		composed := Matcher(func(info *BuildInfo) bool {
+			return base(info) && channel(info)
+		})
+		config.Matcher = &composed
+	}
+	if !config.AllowMajorJump && config.CurrentVersion != "" {
+		if current, err := semver.Parse(config.CurrentVersion); err == nil {
+			base := *config.Matcher
+			composed := Matcher(func(info *BuildInfo) bool {
+				return base(info) && (info.Version == nil || info.Version.Major <= current.Major)
+			})
+			config.Matcher = &composed
+		}
+	}
+	if config.RolloutMatcher != nil && !config.ForceLatest {
+		base := *config.Matcher
+		rollout := config.RolloutMatcher
+		composed := Matcher(func(info *BuildInfo) bool {
+			return base(info) && rollout.Eligible(info)
+		})
+		config.Matcher = &composed
+	}
 	return Updater{
 		config,
 	}
@@ -113,6 +172,8 @@ func New(config Config) Updater {
 // FindLatest returns the latest eligible build in the repository.
 // It finds all anchors in a html page and try to consider them as a valid build.
 // The latest build that matches, according to the matcher and the sortCriteria order is returned.
+// When a RolloutMatcher is configured, a newer build that hasn't yet reached this node's cohort is
+// skipped in favor of the latest one that has (skip-then-fallback), unless ForceLatest is set.
 func (u Updater) FindLatest() (*BuildInfo, error) {
 	buildList, err := u.fetchBuildList()
 	if err != nil {
@@ -127,18 +188,30 @@ func (u Updater) FindLatest() (*BuildInfo, error) {
 	return buildList[len(buildList)-1], nil
 }
 
-// UpdateTo download the referenced build and move it to the target path
+// UpdateTo downloads the referenced build and installs it over the target path.
+// The download is written to a temporary file (TmpPattern), verified, then moved next to the
+// target as "<target>.new" and swapped into place
+// by installUpdate (the swap strategy differs between POSIX, where rename is atomic, and Windows,
+// where the running executable's name must be freed first). The binary it replaces is preserved as
+// "<target>.old" so a failed update can be undone with Rollback.
 func (u Updater) UpdateTo(build *BuildInfo) error {
-	path := u.TargetPath
-	var err error
-	if path == "" {
-		path, err = os.Executable()
-	}
+	path, err := u.targetPath()
 	if err != nil {
-		return fmt.Errorf("cannot find current executable path: %v", err)
+		return err
 	}
+
+	mode := os.FileMode(0750)
+	if info, statErr := os.Stat(path); statErr == nil {
+		mode = info.Mode()
+		if mode&0100 == 0 {
+			// The file exists but isn't owner-executable (e.g. it was never run before);
+			// make it runnable without widening group/other permissions the operator chose.
+			mode |= 0100
+		}
+	}
+
 	tmpPath := fmt.Sprintf(u.TmpPattern, build.File)
-	tmpFile, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0777)
+	tmpFile, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
 	if err != nil {
 		return fmt.Errorf("cannot create temporary file %s: %v", tmpPath, err)
 	}
@@ -155,12 +228,71 @@ func (u Updater) UpdateTo(build *BuildInfo) error {
 	if err != nil {
 		return err
 	}
+	tmpFile.Close()
+
+	if u.Verifier != nil {
+		if err = u.Verifier.Verify(tmpPath, build); err != nil {
+			return fmt.Errorf("verification failed for %s: %v", build.File, err)
+		}
+	}
 
-	err = os.Rename(tmpPath, path)
+	if err = validateExecutable(tmpPath); err != nil {
+		return err
+	}
+
+	newPath := path + ".new"
+	oldPath := path + ".old"
+	os.Remove(newPath)
+	if err = os.Rename(tmpPath, newPath); err != nil {
+		return fmt.Errorf("cannot move temporary file %s to %s: %v", tmpPath, newPath, err)
+	}
+
+	if err = installUpdate(newPath, path, oldPath); err != nil {
+		os.Remove(newPath)
+		return err
+	}
+
+	return nil
+}
+
+// Rollback restores the binary that UpdateTo preserved as "<target>.old", undoing the last update.
+func (u Updater) Rollback() error {
+	path, err := u.targetPath()
 	if err != nil {
-		return fmt.Errorf("cannot move temporary file %s to %s: %s", tmpPath, path, err)
+		return err
+	}
+	oldPath := path + ".old"
+	if _, err := os.Stat(oldPath); err != nil {
+		return fmt.Errorf("no previous version to roll back to at %s: %v", oldPath, err)
 	}
+	return rollbackUpdate(oldPath, path)
+}
 
+// targetPath resolves the path of the binary to update, defaulting to the current executable.
+func (u Updater) targetPath() (string, error) {
+	if u.TargetPath != "" {
+		return u.TargetPath, nil
+	}
+	path, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("cannot find current executable path: %v", err)
+	}
+	return path, nil
+}
+
+// validateExecutable rejects a downloaded file that is empty or, on POSIX, missing the executable
+// bit, before it is swapped into place.
+func validateExecutable(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("cannot stat downloaded file %s: %v", path, err)
+	}
+	if info.Size() == 0 {
+		return fmt.Errorf("downloaded file %s is empty", path)
+	}
+	if runtime.GOOS != "windows" && info.Mode()&0111 == 0 {
+		return fmt.Errorf("downloaded file %s is not executable", path)
+	}
 	return nil
 }
 
@@ -169,7 +301,11 @@ func (build *BuildInfo) NewerThan(version string) bool {
 	if build.Version == nil {
 		return false
 	}
-	return semver.New(version).LessThan(*build.Version)
+	current, err := semver.Parse(version)
+	if err != nil {
+		return false
+	}
+	return current.LT(*build.Version)
 }
 
 func nameCurrentOsArchMatcher(name string) Matcher {
@@ -186,51 +322,23 @@ func (by sortCriteria) sort(buildList []*BuildInfo) {
 	sort.Sort(sorter)
 }
 
+// fetchBuildList lists every candidate in the repository via the configured RepositoryLister and
+// keeps the ones accepted by Matcher.
 func (u Updater) fetchBuildList() ([]*BuildInfo, error) {
-	list := make([]*BuildInfo, 0)
-	resp, err := http.Get(u.Repository)
+	builds, err := u.Lister.List()
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	re := regexp.MustCompile("<a [^>]*href=\"([^\"]+)\"[^>]*>([^<]+)</a>")
-	matches := re.FindAllSubmatch(body, -1)
-	for _, match := range matches {
-		buildInfo := u.tokenizeBuild(string(match[2]))
-		if buildInfo != nil && (*u.Matcher)(buildInfo) {
-			buildInfo.URL = string(match[1])
-			list = append(list, buildInfo)
+
+	list := make([]*BuildInfo, 0, len(builds))
+	for _, build := range builds {
+		if (*u.Matcher)(build) {
+			list = append(list, build)
 		}
 	}
 	return list, nil
 }
 
-func (u Updater) tokenizeBuild(buildName string) *BuildInfo {
-	split := strings.Split(strings.TrimSuffix(buildName, ".exe"), u.FieldSeparator)
-	if len(split) != len(u.Fields) {
-		return nil
-	}
-
-	tokens := map[field]string{}
-	for key, field := range u.Fields {
-		tokens[field] = split[key]
-	}
-
-	version, _ := semver.NewVersion(tokens[FieldVersion])
-
-	return &BuildInfo{
-		Name:    tokens[FieldName],
-		Version: version,
-		Os:      tokens[FieldOs],
-		Arch:    tokens[FieldArch],
-		File:    buildName,
-	}
-}
-
 func bySemver(build1, build2 BuildInfo) bool {
 	if build1.Version == nil {
 		return true
@@ -238,7 +346,7 @@ func bySemver(build1, build2 BuildInfo) bool {
 	if build2.Version == nil {
 		return false
 	}
-	return build1.Version.LessThan(*build2.Version)
+	return build1.Version.LT(*build2.Version)
 }
 
 func (s *buildInfoSorter) Less(i, j int) bool {
@@ -0,0 +1,187 @@
+package updater
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/md5"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+)
+
+// Verifier authenticates a downloaded build before it is installed. path is the location of the
+// downloaded file on disk, build is the repository entry it was fetched from.
+type Verifier interface {
+	Verify(path string, build *BuildInfo) error
+}
+
+// ChecksumVerifier verifies a downloaded build against a checksum, either one already known from
+// the repository listing (BuildInfo.MD5 or BuildInfo.SHA256) or fetched from the build's sidecar
+// file (BuildInfo.MD5URL or BuildInfo.SHA256URL).
+type ChecksumVerifier struct {
+	algorithm func() hash.Hash
+	known     func(build *BuildInfo) string
+	sidecar   func(build *BuildInfo) string
+}
+
+// NewMD5Verifier returns a ChecksumVerifier checking downloads against an MD5 checksum.
+func NewMD5Verifier() *ChecksumVerifier {
+	return &ChecksumVerifier{
+		algorithm: md5.New,
+		known:     func(build *BuildInfo) string { return build.MD5 },
+		sidecar:   func(build *BuildInfo) string { return build.MD5URL },
+	}
+}
+
+// NewSHA256Verifier returns a ChecksumVerifier checking downloads against a SHA256 checksum.
+func NewSHA256Verifier() *ChecksumVerifier {
+	return &ChecksumVerifier{
+		algorithm: sha256.New,
+		known:     func(build *BuildInfo) string { return build.SHA256 },
+		sidecar:   func(build *BuildInfo) string { return build.SHA256URL },
+	}
+}
+
+// Verify implements Verifier.
+func (v *ChecksumVerifier) Verify(path string, build *BuildInfo) error {
+	expected, err := v.expected(build)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("cannot open %s for checksum verification: %v", path, err)
+	}
+	defer file.Close()
+
+	h := v.algorithm()
+	if _, err := io.Copy(h, file); err != nil {
+		return fmt.Errorf("cannot read %s for checksum verification: %v", path, err)
+	}
+
+	actual := fmt.Sprintf("%x", h.Sum(nil))
+	if !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", build.File, expected, actual)
+	}
+	return nil
+}
+
+func (v *ChecksumVerifier) expected(build *BuildInfo) (string, error) {
+	if sum := v.known(build); sum != "" {
+		return sum, nil
+	}
+	url := v.sidecar(build)
+	if url == "" {
+		return "", fmt.Errorf("no checksum available for %s", build.File)
+	}
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("cannot fetch checksum for %s: %v", build.File, err)
+	}
+	defer resp.Body.Close()
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("cannot read checksum for %s: %v", build.File, err)
+	}
+
+	// sha256sum/md5sum-style sidecars start with "<hex>  <filename>"; a bare hex digest is also
+	// accepted.
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty checksum sidecar for %s", build.File)
+	}
+	return fields[0], nil
+}
+
+// Ed25519Verifier verifies a downloaded build against a raw detached ed25519 signature fetched
+// from the build's SignatureURL sidecar file.
+type Ed25519Verifier struct {
+	PublicKey ed25519.PublicKey
+}
+
+// NewEd25519Verifier returns an Ed25519Verifier checking downloads against detached signatures
+// produced with the private key matching publicKey.
+func NewEd25519Verifier(publicKey ed25519.PublicKey) *Ed25519Verifier {
+	return &Ed25519Verifier{PublicKey: publicKey}
+}
+
+// Verify implements Verifier.
+func (v *Ed25519Verifier) Verify(path string, build *BuildInfo) error {
+	if build.SignatureURL == "" {
+		return fmt.Errorf("no signature available for %s", build.File)
+	}
+
+	resp, err := http.Get(build.SignatureURL)
+	if err != nil {
+		return fmt.Errorf("cannot fetch signature for %s: %v", build.File, err)
+	}
+	defer resp.Body.Close()
+	signature, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("cannot read signature for %s: %v", build.File, err)
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("cannot read %s for signature verification: %v", path, err)
+	}
+
+	if !ed25519.Verify(v.PublicKey, content, signature) {
+		return fmt.Errorf("signature mismatch for %s", build.File)
+	}
+	return nil
+}
+
+// PGPVerifier verifies a downloaded build against an armored or binary detached PGP signature
+// fetched from the build's SignatureURL sidecar file, checked against KeyRing.
+type PGPVerifier struct {
+	KeyRing openpgp.EntityList
+}
+
+// NewPGPVerifier returns a PGPVerifier checking downloads against detached signatures made by one
+// of the keys in keyRing.
+func NewPGPVerifier(keyRing openpgp.EntityList) *PGPVerifier {
+	return &PGPVerifier{KeyRing: keyRing}
+}
+
+// Verify implements Verifier.
+func (v *PGPVerifier) Verify(path string, build *BuildInfo) error {
+	if build.SignatureURL == "" {
+		return fmt.Errorf("no signature available for %s", build.File)
+	}
+
+	resp, err := http.Get(build.SignatureURL)
+	if err != nil {
+		return fmt.Errorf("cannot fetch signature for %s: %v", build.File, err)
+	}
+	defer resp.Body.Close()
+	signature, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("cannot read signature for %s: %v", build.File, err)
+	}
+
+	content, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("cannot open %s for signature verification: %v", path, err)
+	}
+	defer content.Close()
+
+	if _, err := openpgp.CheckDetachedSignature(v.KeyRing, content, bytes.NewReader(signature)); err != nil {
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("signature verification failed for %s: %v", build.File, err)
+		}
+		if _, armoredErr := openpgp.CheckArmoredDetachedSignature(v.KeyRing, content, bytes.NewReader(signature)); armoredErr != nil {
+			return fmt.Errorf("signature verification failed for %s: %v", build.File, armoredErr)
+		}
+	}
+	return nil
+}